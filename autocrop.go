@@ -1,6 +1,7 @@
 package autocrop
 
 import (
+	"github.com/mandykoh/autocrop/kernel"
 	"github.com/mandykoh/prism/srgb"
 	"image"
 	"image/draw"
@@ -53,14 +54,23 @@ func BoundsForThreshold(img *image.NRGBA, energyThreshold float32) image.Rectang
 // Energies returns the total row and column energies for the specified region
 // of an image.
 func Energies(img *image.NRGBA, r image.Rectangle) (cols, rows []float32) {
+	return energiesWithKernel(img, r, EnergyKernel{}, EnergyNormL1)
+}
+
+// energiesWithKernel is Energies, but measuring each pixel's energy using
+// the given kernel(s) and norm instead of the default gradient.
+func energiesWithKernel(img *image.NRGBA, r image.Rectangle, k EnergyKernel, norm EnergyNorm) (cols, rows []float32) {
 
-	// Need 1 pixel more luminance data on each side so that all energy
-	// calculations are for pixels with a full set of neighbours.
+	// Need radius pixels more luminance data on each side so that all
+	// energy calculations are for pixels with a full set of neighbours,
+	// where radius is the largest radius of any kernel in play (1 for the
+	// default gradient).
+	radius := k.radius()
 	luminanceBounds := r
-	luminanceBounds.Min.X--
-	luminanceBounds.Min.Y--
-	luminanceBounds.Max.X++
-	luminanceBounds.Max.Y++
+	luminanceBounds.Min.X -= radius
+	luminanceBounds.Min.Y -= radius
+	luminanceBounds.Max.X += radius
+	luminanceBounds.Max.Y += radius
 
 	luminances, alphas := luminancesAndAlphas(img, luminanceBounds)
 
@@ -70,7 +80,7 @@ func Energies(img *image.NRGBA, r image.Rectangle) (cols, rows []float32) {
 	// Calculate total column and row energies
 	for i, row := r.Min.Y, 0; i < r.Max.Y; i, row = i+1, row+1 {
 		for j, col := r.Min.X, 0; j < r.Max.X; j, col = j+1, col+1 {
-			e := energy(luminances, alphas, luminanceBounds.Dx(), col+1, row+1)
+			e := energy(luminances, alphas, luminanceBounds.Dx(), col+radius, row+radius, k, norm)
 			cols[col] += e
 			rows[row] += e
 		}
@@ -96,16 +106,54 @@ func colourAt(img *image.NRGBA, x, y int) (col srgb.Color, alpha float32) {
 	return srgb.ColorFromNRGBA(img.NRGBAAt(x, y))
 }
 
-func energy(luminances, alphas []float32, width int, x, y int) float32 {
+func energy(luminances, alphas []float32, width int, x, y int, k EnergyKernel, norm EnergyNorm) float32 {
 	center := y*width + x
 
-	// North west + west + south west - north east - east - south east
-	eX := luminances[center-width-1] + luminances[center-1] + luminances[center+width-1] - luminances[center-width+1] - luminances[center+1] - luminances[center+width+1]
+	if k.X == nil && k.Y == nil {
+		// North west + west + south west - north east - east - south east
+		eX := luminances[center-width-1] + luminances[center-1] + luminances[center+width-1] - luminances[center-width+1] - luminances[center+1] - luminances[center+width+1]
+
+		// North west + north + north east - south west - south - south east
+		eY := luminances[center-width-1] + luminances[center-width] + luminances[center-width+1] - luminances[center+width-1] - luminances[center+width] - luminances[center+width+1]
+
+		return float32((math.Abs(float64(eX)) + math.Abs(float64(eY))) * float64(alphas[center]))
+	}
+
+	gx := applyKernel(luminances, width, x, y, k.X)
+
+	if k.Y == nil {
+		return float32(math.Abs(float64(gx))) * alphas[center]
+	}
 
-	// North west + north + north east - south west - south - south east
-	eY := luminances[center-width-1] + luminances[center-width] + luminances[center-width+1] - luminances[center+width-1] - luminances[center+width] - luminances[center+width+1]
+	gy := applyKernel(luminances, width, x, y, k.Y)
+
+	var magnitude float64
+	if norm == EnergyNormL2 {
+		magnitude = math.Sqrt(float64(gx)*float64(gx) + float64(gy)*float64(gy))
+	} else {
+		magnitude = math.Abs(float64(gx)) + math.Abs(float64(gy))
+	}
+
+	return float32(magnitude) * alphas[center]
+}
+
+// applyKernel convolves k over the 2*k.Radius()+1 neighbourhood of
+// luminances centred on (x, y).
+func applyKernel(luminances []float32, width int, x, y int, k kernel.Kernel) float32 {
+	weights := k.Weights()
+	radius := k.Radius()
+
+	var sum float32
+	i := 0
+	for dy := -radius; dy <= radius; dy++ {
+		rowStart := (y+dy)*width + x
+		for dx := -radius; dx <= radius; dx++ {
+			sum += weights[i] * luminances[rowStart+dx]
+			i++
+		}
+	}
 
-	return float32((math.Abs(float64(eX)) + math.Abs(float64(eY))) * float64(alphas[center]))
+	return sum
 }
 
 func findFirstEnergyBound(energies []float32, maxEnergy, threshold float32) (bound int) {
@@ -1,6 +1,7 @@
 package autocrop
 
 import (
+	"github.com/mandykoh/autocrop/kernel"
 	"image"
 	"image/color"
 	"image/draw"
@@ -21,6 +22,26 @@ func BenchmarkEnergySummation(b *testing.B) {
 	}
 }
 
+func BenchmarkEnergySummationParallelSingleWorker(b *testing.B) {
+	b.StopTimer()
+	img := loadTestImage("avocado.png", nil)
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		BoundsForThresholdWithOptions(img, 0.01, Options{Parallelism: 1})
+	}
+}
+
+func BenchmarkEnergySummationParallel(b *testing.B) {
+	b.StopTimer()
+	img := loadTestImage("avocado.png", nil)
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		BoundsForThresholdWithOptions(img, 0.01, Options{})
+	}
+}
+
 func TestBoundsForThreshold(t *testing.T) {
 
 	t.Run("returns bounds for simple image with plain background cropped out", func(t *testing.T) {
@@ -134,6 +155,138 @@ func TestBoundsForThreshold(t *testing.T) {
 	})
 }
 
+func TestBoundsForThresholdWithOptions(t *testing.T) {
+
+	t.Run("matches BoundsForThreshold regardless of parallelism", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		expected := BoundsForThreshold(img, 0.2)
+
+		for _, parallelism := range []int{0, 1, 2, 8} {
+			actual := BoundsForThresholdWithOptions(img, 0.2, Options{Parallelism: parallelism})
+
+			if expected != actual {
+				t.Errorf("Expected bounds %v with parallelism %d but got %v", expected, parallelism, actual)
+			}
+		}
+	})
+
+	t.Run("crops using a configured Sobel kernel pair", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		result := BoundsForThresholdWithOptions(img, 0.2, Options{
+			EnergyKernel: EnergyKernel{X: kernel.SobelX, Y: kernel.SobelY},
+			EnergyNorm:   EnergyNormL2,
+		})
+
+		if expected, actual := 70, result.Dx(); expected != actual {
+			t.Errorf("Expected cropped bounds to be %d pixels wide but was %d", expected, actual)
+		}
+		if expected, actual := 70, result.Dy(); expected != actual {
+			t.Errorf("Expected cropped bounds to be %d pixels tall but was %d", expected, actual)
+		}
+	})
+
+	t.Run("crops using a single isotropic kernel", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		result := BoundsForThresholdWithOptions(img, 0.2, Options{
+			EnergyKernel: EnergyKernel{X: kernel.Laplacian5},
+		})
+
+		if expected, actual := 70, result.Dx(); expected != actual {
+			t.Errorf("Expected cropped bounds to be %d pixels wide but was %d", expected, actual)
+		}
+		if expected, actual := 70, result.Dy(); expected != actual {
+			t.Errorf("Expected cropped bounds to be %d pixels tall but was %d", expected, actual)
+		}
+	})
+
+	t.Run("crops using a custom kernel with a radius greater than 1", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		wideSobelX := kernel.New([]float32{
+			-1, -1, 0, 1, 1,
+			-2, -2, 0, 2, 2,
+			-3, -3, 0, 3, 3,
+			-2, -2, 0, 2, 2,
+			-1, -1, 0, 1, 1,
+		}, 2)
+
+		result := BoundsForThresholdWithOptions(img, 0.2, Options{
+			EnergyKernel: EnergyKernel{X: wideSobelX},
+		})
+
+		// A radius-2 kernel spreads each pixel's energy contribution over a
+		// wider neighbourhood than the default radius-1 gradient, so the
+		// threshold crossing can land up to the kernel's radius away from
+		// the tight 70x70 crop found by the default kernel; it shouldn't,
+		// however, fail to crop at all (e.g. by reading out-of-bounds
+		// pixels as a spurious energy spike at the image edges).
+		const radius = 2
+
+		if result.Eq(img.Bounds()) {
+			t.Fatalf("Expected a crop smaller than the full image bounds %v but got %v", img.Bounds(), result)
+		}
+		if dx := result.Dx(); dx < 70 || dx > 70+2*radius {
+			t.Errorf("Expected cropped bounds to be within %d pixels of 70 wide but was %d", 2*radius, dx)
+		}
+		if dy := result.Dy(); dy < 70 || dy > 70+2*radius {
+			t.Errorf("Expected cropped bounds to be within %d pixels of 70 tall but was %d", 2*radius, dy)
+		}
+	})
+}
+
+func TestEnergyMap(t *testing.T) {
+
+	t.Run("BoundsForThreshold matches BoundsForThreshold for a sweep of thresholds", func(t *testing.T) {
+		img := loadTestImage("radial-gradient.png", t)
+
+		m := NewEnergyMap(img)
+
+		thresholds := []float32{0.1, 0.2, 0.3, 0.4, 0.5, 0.9}
+
+		for _, threshold := range thresholds {
+			expected := BoundsForThreshold(img, threshold)
+			actual := m.BoundsForThreshold(threshold)
+
+			if expected != actual {
+				t.Errorf("Expected bounds %v for threshold %f but got %v", expected, threshold, actual)
+			}
+		}
+	})
+
+	t.Run("BoundsForThresholds returns one result per threshold", func(t *testing.T) {
+		img := loadTestImage("radial-gradient.png", t)
+
+		m := NewEnergyMap(img)
+		thresholds := []float32{0.1, 0.2, 0.3}
+
+		results := m.BoundsForThresholds(thresholds)
+
+		if expected, actual := len(thresholds), len(results); expected != actual {
+			t.Fatalf("Expected %d results but got %d", expected, actual)
+		}
+
+		for i, threshold := range thresholds {
+			if expected, actual := m.BoundsForThreshold(threshold), results[i]; expected != actual {
+				t.Errorf("Expected bounds %v for threshold %f but got %v", expected, threshold, actual)
+			}
+		}
+	})
+
+	t.Run("works with a 1x1 image", func(t *testing.T) {
+		img := loadTestImage("1x1.png", t)
+
+		m := NewEnergyMap(img)
+		result := m.BoundsForThreshold(0.01)
+
+		if expected, actual := img.Bounds(), result; expected != actual {
+			t.Errorf("Expected bounds %v but got %v", expected, actual)
+		}
+	})
+}
+
 func TestToThreshold(t *testing.T) {
 
 	t.Run("returns complex image with transparent background cropped out", func(t *testing.T) {
@@ -163,6 +316,165 @@ func TestToThreshold(t *testing.T) {
 	})
 }
 
+func TestBoundsForThresholdConstrained(t *testing.T) {
+
+	t.Run("expands to satisfy an aspect ratio", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{AspectRatio: 2})
+
+		if expected, actual := float32(2), float32(result.Dx())/float32(result.Dy()); expected != actual {
+			t.Errorf("Expected aspect ratio %f but got %f (%v)", expected, actual, result)
+		}
+	})
+
+	t.Run("preserves aspect ratio when growing further to meet a minimum size", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		withRatioOnly := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{AspectRatio: 2})
+
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{
+			AspectRatio: 2,
+			MinSize:     image.Pt(1, withRatioOnly.Dy()+10),
+		})
+
+		if expected, actual := float32(2), float32(result.Dx())/float32(result.Dy()); expected != actual {
+			t.Errorf("Expected aspect ratio %f to be preserved after MinSize growth but got %f (%v)", expected, actual, result)
+		}
+		if result.Dy() < withRatioOnly.Dy()+10 {
+			t.Errorf("Expected height to have grown to at least %d but got %d", withRatioOnly.Dy()+10, result.Dy())
+		}
+	})
+
+	t.Run("adds padding around the energy-based crop", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		tight := BoundsForThreshold(img, 0.2)
+		padded := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{Padding: 0.1})
+
+		if !tight.In(padded) {
+			t.Errorf("Expected padded bounds %v to contain tight bounds %v", padded, tight)
+		}
+		if padded.Eq(tight) {
+			t.Errorf("Expected padding to grow the bounds beyond %v but got %v", tight, padded)
+		}
+	})
+
+	t.Run("grows to meet a minimum size", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{MinSize: image.Pt(74, 74)})
+
+		if expected, actual := 74, result.Dx(); expected != actual {
+			t.Errorf("Expected width %d but got %d", expected, actual)
+		}
+		if expected, actual := 74, result.Dy(); expected != actual {
+			t.Errorf("Expected height %d but got %d", expected, actual)
+		}
+	})
+
+	t.Run("AnchorTopLeft keeps the top-left corner fixed while growing to meet a minimum size", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		tight := BoundsForThreshold(img, 0.2)
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{
+			MinSize: image.Pt(tight.Dx()+4, tight.Dy()+4),
+			Anchor:  AnchorTopLeft,
+		})
+
+		if expected, actual := tight.Min, result.Min; expected != actual {
+			t.Errorf("Expected top-left corner to stay fixed at %v but got %v", expected, actual)
+		}
+		if result.Max.X <= tight.Max.X || result.Max.Y <= tight.Max.Y {
+			t.Errorf("Expected growth to the right and down from %v but got %v", tight, result)
+		}
+	})
+
+	t.Run("AnchorTopRight keeps the top-right corner fixed while growing to meet a minimum size", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		tight := BoundsForThreshold(img, 0.2)
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{
+			MinSize: image.Pt(tight.Dx()+4, tight.Dy()+4),
+			Anchor:  AnchorTopRight,
+		})
+
+		if expected, actual := image.Pt(tight.Max.X, tight.Min.Y), image.Pt(result.Max.X, result.Min.Y); expected != actual {
+			t.Errorf("Expected top-right corner to stay fixed at %v but got %v", expected, actual)
+		}
+		if result.Min.X >= tight.Min.X || result.Max.Y <= tight.Max.Y {
+			t.Errorf("Expected growth to the left and down from %v but got %v", tight, result)
+		}
+	})
+
+	t.Run("AnchorBottomLeft keeps the bottom-left corner fixed while growing to meet a minimum size", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		tight := BoundsForThreshold(img, 0.2)
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{
+			MinSize: image.Pt(tight.Dx()+4, tight.Dy()+4),
+			Anchor:  AnchorBottomLeft,
+		})
+
+		if expected, actual := image.Pt(tight.Min.X, tight.Max.Y), image.Pt(result.Min.X, result.Max.Y); expected != actual {
+			t.Errorf("Expected bottom-left corner to stay fixed at %v but got %v", expected, actual)
+		}
+		if result.Max.X <= tight.Max.X || result.Min.Y >= tight.Min.Y {
+			t.Errorf("Expected growth to the right and up from %v but got %v", tight, result)
+		}
+	})
+
+	t.Run("AnchorBottomRight keeps the bottom-right corner fixed while growing to meet a minimum size", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		tight := BoundsForThreshold(img, 0.2)
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{
+			MinSize: image.Pt(tight.Dx()+4, tight.Dy()+4),
+			Anchor:  AnchorBottomRight,
+		})
+
+		if expected, actual := tight.Max, result.Max; expected != actual {
+			t.Errorf("Expected bottom-right corner to stay fixed at %v but got %v", expected, actual)
+		}
+		if result.Min.X >= tight.Min.X || result.Min.Y >= tight.Min.Y {
+			t.Errorf("Expected growth to the left and up from %v but got %v", tight, result)
+		}
+	})
+
+	t.Run("never exceeds the original image bounds", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		result := BoundsForThresholdConstrained(img, 0.2, ConstrainOptions{
+			AspectRatio: 3,
+			Padding:     0.5,
+			MinSize:     image.Pt(1000, 1000),
+		})
+
+		if !result.In(img.Bounds()) {
+			t.Errorf("Expected constrained bounds %v to stay within image bounds %v", result, img.Bounds())
+		}
+	})
+}
+
+func TestToThresholdConstrained(t *testing.T) {
+
+	t.Run("returns an image matching BoundsForThresholdConstrained", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		opts := ConstrainOptions{AspectRatio: 1.5}
+		expectedBounds := BoundsForThresholdConstrained(img, 0.2, opts)
+
+		result := ToThresholdConstrained(img, 0.2, opts)
+
+		if expected, actual := expectedBounds.Dx(), result.Bounds().Dx(); expected != actual {
+			t.Errorf("Expected width %d but got %d", expected, actual)
+		}
+		if expected, actual := expectedBounds.Dy(), result.Bounds().Dy(); expected != actual {
+			t.Errorf("Expected height %d but got %d", expected, actual)
+		}
+	})
+}
+
 func loadTestImage(fileName string, t *testing.T) *image.NRGBA {
 	if t != nil {
 		t.Helper()
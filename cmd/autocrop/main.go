@@ -3,9 +3,6 @@ package main
 import (
 	"fmt"
 	"github.com/mandykoh/autocrop"
-	"image"
-	"image/draw"
-	_ "image/jpeg"
 	"image/png"
 	"os"
 	"strconv"
@@ -28,12 +25,6 @@ func main() {
 
 	outFilePath := os.Args[2]
 
-	img, _, err := image.Decode(imgFile)
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "could not read image: %v\n", err)
-		os.Exit(2)
-	}
-
 	threshold := float32(0.01)
 	if len(os.Args) > 3 {
 		val, err := strconv.ParseFloat(os.Args[3], 32)
@@ -45,10 +36,14 @@ func main() {
 		threshold = float32(val)
 	}
 
-	nrgbaImg := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
-	draw.Draw(nrgbaImg, nrgbaImg.Bounds(), img, img.Bounds().Min, draw.Src)
-
-	result := autocrop.ToThreshold(nrgbaImg, threshold)
+	// ReadFromReader decodes the image and corrects its orientation using
+	// any EXIF tag before cropping, so auto-cropped phone photos don't come
+	// out sideways.
+	result, err := autocrop.ReadFromReader(imgFile, threshold, nil)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "could not read image: %v\n", err)
+		os.Exit(2)
+	}
 
 	outFile, err := os.Create(outFilePath)
 	if err != nil {
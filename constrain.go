@@ -0,0 +1,316 @@
+package autocrop
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Anchor controls how extra space is distributed between opposite edges
+// when a crop needs to grow to satisfy ConstrainOptions.MinSize.
+type Anchor int
+
+const (
+	// AnchorCenter distributes growth evenly between opposite edges.
+	AnchorCenter Anchor = iota
+
+	// AnchorTopLeft keeps the top-left corner fixed, growing only to the
+	// right and down.
+	AnchorTopLeft
+
+	// AnchorTopRight keeps the top-right corner fixed, growing only to the
+	// left and down.
+	AnchorTopRight
+
+	// AnchorBottomLeft keeps the bottom-left corner fixed, growing only to
+	// the right and up.
+	AnchorBottomLeft
+
+	// AnchorBottomRight keeps the bottom-right corner fixed, growing only
+	// to the left and up.
+	AnchorBottomRight
+)
+
+// ConstrainOptions configures BoundsForThresholdConstrained and
+// ToThresholdConstrained.
+type ConstrainOptions struct {
+
+	// AspectRatio is the target width/height ratio for the result, e.g.
+	// 1 for square or 1.777 for 16:9. Zero means no aspect ratio
+	// constraint.
+	AspectRatio float32
+
+	// Padding re-expands the energy-based crop by this fraction of its
+	// width and height, giving the result some breathing room around the
+	// detected content. E.g. 0.05 adds a 5% margin on each side.
+	Padding float32
+
+	// MinSize is the size the crop is grown, anchored per Anchor, to meet
+	// where the image is large enough to do so. When AspectRatio is also
+	// set, the result can end up smaller than MinSize; see
+	// BoundsForThresholdConstrained.
+	MinSize image.Point
+
+	// Anchor controls how growth to satisfy MinSize is distributed between
+	// opposite edges.
+	Anchor Anchor
+}
+
+// BoundsForThresholdConstrained returns the bounds for a crop of img up to
+// the given energy threshold, the same as BoundsForThreshold, adjusted to
+// satisfy opts.
+//
+// The energy-based crop is first re-expanded by opts.Padding, then grown
+// or shrunk to satisfy opts.AspectRatio (biasing any growth toward
+// whichever side has lower energy, so the high-energy background that was
+// just cropped out isn't reintroduced), then grown to meet opts.MinSize.
+// Growing to meet opts.MinSize can change the ratio, so opts.AspectRatio
+// is then reapplied the same way: the other axis is grown to match where
+// the image is large enough, and otherwise the longer axis is contracted,
+// which can leave the result smaller than opts.MinSize. This second pass
+// does not follow opts.Anchor.
+func BoundsForThresholdConstrained(img *image.NRGBA, energyThreshold float32, opts ConstrainOptions) image.Rectangle {
+
+	full := img.Bounds()
+
+	m := NewEnergyMap(img)
+	crop := m.BoundsForThreshold(energyThreshold)
+
+	crop = applyPadding(full, crop, opts.Padding)
+	crop = constrainAspectRatio(full, crop, m, opts.AspectRatio)
+	crop = applyMinSize(full, crop, opts.MinSize, opts.Anchor)
+	crop = constrainAspectRatio(full, crop, m, opts.AspectRatio)
+
+	return crop
+}
+
+// ToThresholdConstrained returns an image cropped using the bounds
+// provided by BoundsForThresholdConstrained.
+func ToThresholdConstrained(img *image.NRGBA, energyThreshold float32, opts ConstrainOptions) *image.NRGBA {
+	crop := BoundsForThresholdConstrained(img, energyThreshold, opts)
+	resultImg := image.NewNRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(resultImg, resultImg.Bounds(), img, crop.Min, draw.Src)
+	return resultImg
+}
+
+func applyPadding(full, crop image.Rectangle, padding float32) image.Rectangle {
+	if padding <= 0 {
+		return crop
+	}
+
+	padX := int(float32(crop.Dx()) * padding)
+	padY := int(float32(crop.Dy()) * padding)
+
+	crop.Min.X -= padX
+	crop.Min.Y -= padY
+	crop.Max.X += padX
+	crop.Max.Y += padY
+
+	return crop.Intersect(full)
+}
+
+func constrainAspectRatio(full, crop image.Rectangle, m *EnergyMap, aspectRatio float32) image.Rectangle {
+	if aspectRatio <= 0 || crop.Dx() == 0 || crop.Dy() == 0 {
+		return crop
+	}
+
+	if ratio := float32(crop.Dx()) / float32(crop.Dy()); ratio < aspectRatio {
+		crop = growWidth(full, crop, int(float32(crop.Dy())*aspectRatio), m)
+	} else if ratio > aspectRatio {
+		crop = growHeight(full, crop, int(float32(crop.Dx())/aspectRatio), m)
+	}
+
+	// Growing the shorter axis may have been clamped by the image bounds;
+	// if so, contract the other axis instead so the result still matches
+	// the target ratio.
+	if ratio := float32(crop.Dx()) / float32(crop.Dy()); ratio > aspectRatio {
+		crop = shrinkWidth(crop, int(float32(crop.Dy())*aspectRatio))
+	} else if ratio < aspectRatio {
+		crop = shrinkHeight(crop, int(float32(crop.Dx())/aspectRatio))
+	}
+
+	return crop
+}
+
+// growWidth grows crop's width to targetWidth, biasing growth toward
+// whichever side immediately outside the crop has lower energy.
+func growWidth(full, crop image.Rectangle, targetWidth int, m *EnergyMap) image.Rectangle {
+	grow := targetWidth - crop.Dx()
+	if grow <= 0 {
+		return crop
+	}
+
+	growLeft, growRight := splitGrowth(grow, m.columnEnergy(crop.Min.X-1), m.columnEnergy(crop.Max.X))
+
+	crop.Min.X -= growLeft
+	crop.Max.X += growRight
+
+	if overflow := full.Min.X - crop.Min.X; overflow > 0 {
+		crop.Min.X = full.Min.X
+		crop.Max.X += overflow
+	}
+	if overflow := crop.Max.X - full.Max.X; overflow > 0 {
+		crop.Max.X = full.Max.X
+		crop.Min.X -= overflow
+	}
+	crop.Min.X = maxInt(crop.Min.X, full.Min.X)
+	crop.Max.X = minInt(crop.Max.X, full.Max.X)
+
+	return crop
+}
+
+// growHeight grows crop's height to targetHeight, biasing growth toward
+// whichever side immediately outside the crop has lower energy.
+func growHeight(full, crop image.Rectangle, targetHeight int, m *EnergyMap) image.Rectangle {
+	grow := targetHeight - crop.Dy()
+	if grow <= 0 {
+		return crop
+	}
+
+	growTop, growBottom := splitGrowth(grow, m.rowEnergy(crop.Min.Y-1), m.rowEnergy(crop.Max.Y))
+
+	crop.Min.Y -= growTop
+	crop.Max.Y += growBottom
+
+	if overflow := full.Min.Y - crop.Min.Y; overflow > 0 {
+		crop.Min.Y = full.Min.Y
+		crop.Max.Y += overflow
+	}
+	if overflow := crop.Max.Y - full.Max.Y; overflow > 0 {
+		crop.Max.Y = full.Max.Y
+		crop.Min.Y -= overflow
+	}
+	crop.Min.Y = maxInt(crop.Min.Y, full.Min.Y)
+	crop.Max.Y = minInt(crop.Max.Y, full.Max.Y)
+
+	return crop
+}
+
+// splitGrowth divides amount between two opposite sides, giving the larger
+// share to whichever side has the lower energy (so growth favours
+// re-absorbing plain background over busy detail). Ties split evenly.
+func splitGrowth(amount int, energyNear, energyFar float32) (near, far int) {
+	total := energyNear + energyFar
+	if total == 0 {
+		near = amount / 2
+		return near, amount - near
+	}
+
+	near = int(float32(amount) * energyFar / total)
+	return near, amount - near
+}
+
+func shrinkWidth(crop image.Rectangle, targetWidth int) image.Rectangle {
+	shrink := crop.Dx() - targetWidth
+	if shrink <= 0 {
+		return crop
+	}
+
+	left := shrink / 2
+	crop.Min.X += left
+	crop.Max.X -= shrink - left
+	return crop
+}
+
+func shrinkHeight(crop image.Rectangle, targetHeight int) image.Rectangle {
+	shrink := crop.Dy() - targetHeight
+	if shrink <= 0 {
+		return crop
+	}
+
+	top := shrink / 2
+	crop.Min.Y += top
+	crop.Max.Y -= shrink - top
+	return crop
+}
+
+func applyMinSize(full, crop image.Rectangle, minSize image.Point, anchor Anchor) image.Rectangle {
+	if minSize.X > crop.Dx() {
+		crop = growToWidth(full, crop, minSize.X, anchor)
+	}
+	if minSize.Y > crop.Dy() {
+		crop = growToHeight(full, crop, minSize.Y, anchor)
+	}
+	return crop
+}
+
+func growToWidth(full, crop image.Rectangle, width int, anchor Anchor) image.Rectangle {
+	grow := width - crop.Dx()
+	if grow <= 0 {
+		return crop
+	}
+
+	var growLeft, growRight int
+	switch anchor {
+	case AnchorTopLeft, AnchorBottomLeft:
+		growRight = grow
+	case AnchorTopRight, AnchorBottomRight:
+		growLeft = grow
+	default:
+		growLeft = grow / 2
+		growRight = grow - growLeft
+	}
+
+	crop.Min.X -= growLeft
+	crop.Max.X += growRight
+
+	if overflow := full.Min.X - crop.Min.X; overflow > 0 {
+		crop.Min.X = full.Min.X
+		crop.Max.X += overflow
+	}
+	if overflow := crop.Max.X - full.Max.X; overflow > 0 {
+		crop.Max.X = full.Max.X
+		crop.Min.X -= overflow
+	}
+	crop.Min.X = maxInt(crop.Min.X, full.Min.X)
+	crop.Max.X = minInt(crop.Max.X, full.Max.X)
+
+	return crop
+}
+
+func growToHeight(full, crop image.Rectangle, height int, anchor Anchor) image.Rectangle {
+	grow := height - crop.Dy()
+	if grow <= 0 {
+		return crop
+	}
+
+	var growTop, growBottom int
+	switch anchor {
+	case AnchorTopLeft, AnchorTopRight:
+		growBottom = grow
+	case AnchorBottomLeft, AnchorBottomRight:
+		growTop = grow
+	default:
+		growTop = grow / 2
+		growBottom = grow - growTop
+	}
+
+	crop.Min.Y -= growTop
+	crop.Max.Y += growBottom
+
+	if overflow := full.Min.Y - crop.Min.Y; overflow > 0 {
+		crop.Min.Y = full.Min.Y
+		crop.Max.Y += overflow
+	}
+	if overflow := crop.Max.Y - full.Max.Y; overflow > 0 {
+		crop.Max.Y = full.Max.Y
+		crop.Min.Y -= overflow
+	}
+	crop.Min.Y = maxInt(crop.Min.Y, full.Min.Y)
+	crop.Max.Y = minInt(crop.Max.Y, full.Max.Y)
+
+	return crop
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
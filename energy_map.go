@@ -0,0 +1,156 @@
+package autocrop
+
+import "image"
+
+// EnergyMap precomputes an image's per-pixel energies as an integral
+// (summed-area) image, so that the column and row energy sums needed by
+// BoundsForThreshold can be read off in O(w+h) afterwards, rather than
+// needing to re-walk every pixel as Energies does. This makes sweeping a
+// range of thresholds — e.g. to find the tightest crop that still retains
+// some minimum area — O(w+h) per threshold instead of O(w*h).
+type EnergyMap struct {
+	bounds image.Rectangle
+
+	// hasEnergy is false when bounds is too small to have any pixels with
+	// a full set of neighbours (e.g. a 1x1 image), in which case
+	// BoundsForThreshold always returns bounds unchanged.
+	hasEnergy bool
+
+	cols, rows                 []float32
+	maxColEnergy, maxRowEnergy float32
+}
+
+// NewEnergyMap computes an EnergyMap for img.
+func NewEnergyMap(img *image.NRGBA) *EnergyMap {
+
+	bounds := img.Bounds()
+
+	energyCrop := bounds
+	energyCrop.Min.X++
+	energyCrop.Min.Y++
+	energyCrop.Max.X--
+	energyCrop.Max.Y--
+
+	if energyCrop.Empty() {
+		return &EnergyMap{bounds: bounds}
+	}
+
+	m := &EnergyMap{
+		bounds:    bounds,
+		hasEnergy: true,
+		cols:      make([]float32, energyCrop.Dx(), energyCrop.Dx()),
+		rows:      make([]float32, energyCrop.Dy(), energyCrop.Dy()),
+	}
+
+	// integral is indexed relative to energyCrop, one row/column larger in
+	// each dimension so that rectangle sums don't need to special-case the
+	// first row/column.
+	integral := buildEnergyIntegral(img, energyCrop)
+
+	for col := 0; col < energyCrop.Dx(); col++ {
+		m.cols[col] = integralRectSum(integral, col, col+1, 0, energyCrop.Dy())
+	}
+
+	for row := 0; row < energyCrop.Dy(); row++ {
+		m.rows[row] = integralRectSum(integral, 0, energyCrop.Dx(), row, row+1)
+	}
+
+	m.maxColEnergy = findMaxEnergy(m.cols)
+	m.maxRowEnergy = findMaxEnergy(m.rows)
+
+	return m
+}
+
+// buildEnergyIntegral computes S[y][x] = the sum of energy over the
+// half-open rectangle [energyCrop.Min, (energyCrop.Min.X+x,
+// energyCrop.Min.Y+y)), relative to energyCrop. S is one row and column
+// larger than energyCrop so that rectangle sums never need to
+// special-case the first row/column.
+func buildEnergyIntegral(img *image.NRGBA, energyCrop image.Rectangle) [][]float32 {
+
+	var k EnergyKernel
+	radius := k.radius()
+
+	luminanceBounds := energyCrop
+	luminanceBounds.Min.X -= radius
+	luminanceBounds.Min.Y -= radius
+	luminanceBounds.Max.X += radius
+	luminanceBounds.Max.Y += radius
+
+	luminances, alphas := luminancesAndAlphas(img, luminanceBounds)
+
+	integral := make([][]float32, energyCrop.Dy()+1)
+	for i := range integral {
+		integral[i] = make([]float32, energyCrop.Dx()+1)
+	}
+
+	for i, y := energyCrop.Min.Y, 0; i < energyCrop.Max.Y; i, y = i+1, y+1 {
+		for j, x := energyCrop.Min.X, 0; j < energyCrop.Max.X; j, x = j+1, x+1 {
+			e := energy(luminances, alphas, luminanceBounds.Dx(), x+radius, y+radius, k, EnergyNormL1)
+			integral[y+1][x+1] = e + integral[y][x+1] + integral[y+1][x] - integral[y][x]
+		}
+	}
+
+	return integral
+}
+
+func integralRectSum(integral [][]float32, x0, x1, y0, y1 int) float32 {
+	return integral[y1][x1] - integral[y0][x1] - integral[y1][x0] + integral[y0][x0]
+}
+
+// BoundsForThreshold returns the bounds for a crop of the image the
+// EnergyMap was built from, up to the given energy threshold. See
+// BoundsForThreshold for the meaning of energyThreshold.
+func (m *EnergyMap) BoundsForThreshold(energyThreshold float32) image.Rectangle {
+
+	crop := m.bounds
+
+	if !m.hasEnergy {
+		return crop
+	}
+
+	cropLeft := findFirstEnergyBound(m.cols, m.maxColEnergy, energyThreshold)
+	cropRight := findLastEnergyBound(m.cols, m.maxColEnergy, energyThreshold, cropLeft)
+
+	cropTop := findFirstEnergyBound(m.rows, m.maxRowEnergy, energyThreshold)
+	cropBottom := findLastEnergyBound(m.rows, m.maxRowEnergy, energyThreshold, cropTop)
+
+	crop.Min.X += cropLeft
+	crop.Min.Y += cropTop
+	crop.Max.X -= cropRight
+	crop.Max.Y -= cropBottom
+
+	return crop
+}
+
+// columnEnergy returns the precomputed energy for the column at absolute
+// image x-coordinate x, or 0 if x falls outside the interior that has
+// energy data (e.g. the outermost column on either edge).
+func (m *EnergyMap) columnEnergy(x int) float32 {
+	i := x - (m.bounds.Min.X + 1)
+	if i < 0 || i >= len(m.cols) {
+		return 0
+	}
+	return m.cols[i]
+}
+
+// rowEnergy returns the precomputed energy for the row at absolute image
+// y-coordinate y, or 0 if y falls outside the interior that has energy
+// data (e.g. the outermost row on either edge).
+func (m *EnergyMap) rowEnergy(y int) float32 {
+	i := y - (m.bounds.Min.Y + 1)
+	if i < 0 || i >= len(m.rows) {
+		return 0
+	}
+	return m.rows[i]
+}
+
+// BoundsForThresholds returns the BoundsForThreshold result for each of the
+// given thresholds, computed from the same precomputed energy sums.
+func (m *EnergyMap) BoundsForThresholds(energyThresholds []float32) []image.Rectangle {
+	bounds := make([]image.Rectangle, len(energyThresholds))
+	for i, threshold := range energyThresholds {
+		bounds[i] = m.BoundsForThreshold(threshold)
+	}
+	return bounds
+}
@@ -0,0 +1,201 @@
+package autocrop
+
+import (
+	"github.com/mandykoh/autocrop/kernel"
+	"github.com/mandykoh/prism/srgb"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Options configures optional behaviour for Image and other *WithOptions
+// entry points. A nil *Options is equivalent to an empty Options{}, and
+// selects sensible defaults throughout.
+type Options struct {
+
+	// Parallelism is the number of goroutines used to compute energies.
+	// Zero or negative selects runtime.NumCPU().
+	Parallelism int
+
+	// EnergyKernel selects the gradient kernel(s) used to measure a
+	// pixel's energy. The zero value uses autocrop's default gradient. Set
+	// X and Y to a directional pair (e.g. kernel.SobelX / kernel.SobelY) to
+	// combine two gradients via EnergyNorm, or set only X to use a single
+	// isotropic kernel (e.g. kernel.Laplacian5) directly as the energy.
+	EnergyKernel EnergyKernel
+
+	// EnergyNorm selects how EnergyKernel's X and Y gradients are combined
+	// into a single energy value. Ignored when EnergyKernel has no Y
+	// kernel.
+	EnergyNorm EnergyNorm
+}
+
+// EnergyKernel pairs the kernels used to measure horizontal and vertical
+// gradients when computing a pixel's energy. See Options.EnergyKernel.
+type EnergyKernel struct {
+	X, Y kernel.Kernel
+}
+
+// radius returns the padding needed around a pixel for every configured
+// kernel to have a full neighbourhood to convolve over, defaulting to 1
+// for the built-in gradient used when EnergyKernel is the zero value.
+func (k EnergyKernel) radius() int {
+	radius := 1
+	if k.X != nil {
+		if r := k.X.Radius(); r > radius {
+			radius = r
+		}
+	}
+	if k.Y != nil {
+		if r := k.Y.Radius(); r > radius {
+			radius = r
+		}
+	}
+	return radius
+}
+
+// EnergyNorm selects how an EnergyKernel's X and Y gradients are combined
+// into a single energy value.
+type EnergyNorm int
+
+const (
+	// EnergyNormL1 combines gradients as |Gx| + |Gy|. This is the default.
+	EnergyNormL1 EnergyNorm = iota
+
+	// EnergyNormL2 combines gradients as sqrt(Gx² + Gy²).
+	EnergyNormL2
+)
+
+// Image returns a copy of img cropped to remove its low-energy borders, up
+// to the given energy threshold.
+//
+// Unlike BoundsForThreshold and ToThreshold, Image accepts any
+// image.Image — including RGBA, YCbCr, Gray, Paletted and NRGBA64 — without
+// forcing the whole image through a premultiplied-to-NRGBA conversion, and
+// preserves the input's original colour model in the result where
+// possible, by sharing the original pixel buffer via SubImage.
+//
+// energyThreshold is a value between 0.0 and 1.0 representing the maximum
+// energy to allow to be cropped away before stopping, relative to the
+// maximum energy of the image.
+func Image(img image.Image, energyThreshold float32, opts *Options) (image.Image, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+
+	crop := boundsForThresholdGeneric(img, energyThreshold, o)
+	return cropImage(img, crop), nil
+}
+
+// boundsForThresholdGeneric is the image.Image-based counterpart to
+// BoundsForThresholdWithOptions, used by Image so that callers aren't
+// forced to convert to *image.NRGBA first.
+func boundsForThresholdGeneric(img image.Image, energyThreshold float32, opts Options) image.Rectangle {
+
+	crop := img.Bounds()
+
+	radius := opts.EnergyKernel.radius()
+	energyCrop := crop
+	energyCrop.Min.X += radius
+	energyCrop.Min.Y += radius
+	energyCrop.Max.X -= radius
+	energyCrop.Max.Y -= radius
+
+	if energyCrop.Empty() {
+		return img.Bounds()
+	}
+
+	colEnergies, rowEnergies := parallelEnergiesGeneric(img, energyCrop, opts)
+
+	// Find left and right high energy jumps
+	maxEnergy := findMaxEnergy(colEnergies)
+	cropLeft := findFirstEnergyBound(colEnergies, maxEnergy, energyThreshold)
+	cropRight := findLastEnergyBound(colEnergies, maxEnergy, energyThreshold, cropLeft)
+
+	// Find top and bottom high energy jumps
+	maxEnergy = findMaxEnergy(rowEnergies)
+	cropTop := findFirstEnergyBound(rowEnergies, maxEnergy, energyThreshold)
+	cropBottom := findLastEnergyBound(rowEnergies, maxEnergy, energyThreshold, cropTop)
+
+	// Apply the crop
+	crop.Min.X += cropLeft
+	crop.Min.Y += cropTop
+	crop.Max.X -= cropRight
+	crop.Max.Y -= cropBottom
+
+	return crop
+}
+
+// energiesGeneric is energiesWithKernel, but for an arbitrary image.Image
+// rather than an *image.NRGBA.
+func energiesGeneric(img image.Image, r image.Rectangle, k EnergyKernel, norm EnergyNorm) (cols, rows []float32) {
+
+	// Need radius pixels more luminance data on each side so that all
+	// energy calculations are for pixels with a full set of neighbours.
+	radius := k.radius()
+	luminanceBounds := r
+	luminanceBounds.Min.X -= radius
+	luminanceBounds.Min.Y -= radius
+	luminanceBounds.Max.X += radius
+	luminanceBounds.Max.Y += radius
+
+	luminances, alphas := luminancesAndAlphasGeneric(img, luminanceBounds)
+
+	cols = make([]float32, r.Dx(), r.Dx())
+	rows = make([]float32, r.Dy(), r.Dy())
+
+	// Calculate total column and row energies
+	for i, row := r.Min.Y, 0; i < r.Max.Y; i, row = i+1, row+1 {
+		for j, col := r.Min.X, 0; j < r.Max.X; j, col = j+1, col+1 {
+			e := energy(luminances, alphas, luminanceBounds.Dx(), col+radius, row+radius, k, norm)
+			cols[col] += e
+			rows[row] += e
+		}
+	}
+
+	return cols, rows
+}
+
+func luminancesAndAlphasGeneric(img image.Image, r image.Rectangle) (luminances, alphas []float32) {
+
+	luminances = make([]float32, r.Dx()*r.Dy(), r.Dx()*r.Dy())
+	alphas = make([]float32, r.Dx()*r.Dy(), r.Dx()*r.Dy())
+
+	index := 0
+
+	// Get the luminances and alphas for all pixels
+	for i := r.Min.Y; i < r.Max.Y; i++ {
+		for j := r.Min.X; j < r.Max.X; j++ {
+			c, a := colourAtGeneric(img, j, i)
+			luminances[index] = luminance(c, a)
+			alphas[index] = a
+			index++
+		}
+	}
+
+	return luminances, alphas
+}
+
+func colourAtGeneric(img image.Image, x, y int) (col srgb.Color, alpha float32) {
+	nrgba := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	return srgb.ColorFromNRGBA(nrgba)
+}
+
+// subImager is implemented by all of the standard library's concrete image
+// types. It lets cropImage return a view onto the original pixel buffer,
+// preserving the source's colour model, rather than copying into a new
+// image.
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+func cropImage(img image.Image, crop image.Rectangle) image.Image {
+	if si, ok := img.(subImager); ok {
+		return si.SubImage(crop)
+	}
+
+	result := image.NewNRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(result, result.Bounds(), img, crop.Min, draw.Src)
+	return result
+}
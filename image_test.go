@@ -0,0 +1,81 @@
+package autocrop
+
+import (
+	"github.com/mandykoh/autocrop/kernel"
+	"image"
+	"testing"
+)
+
+func TestImage(t *testing.T) {
+
+	t.Run("matches BoundsForThreshold for an *image.NRGBA input", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		expected := BoundsForThreshold(img, 0.2)
+
+		result, err := Image(img, 0.2, nil)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if actual := result.Bounds(); expected != actual {
+			t.Errorf("Expected bounds %v but got %v", expected, actual)
+		}
+	})
+
+	t.Run("honours opts.EnergyKernel", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		expected := BoundsForThresholdWithOptions(img, 0.2, Options{
+			EnergyKernel: EnergyKernel{X: kernel.Laplacian5},
+		})
+
+		result, err := Image(img, 0.2, &Options{
+			EnergyKernel: EnergyKernel{X: kernel.Laplacian5},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if actual := result.Bounds(); expected != actual {
+			t.Errorf("Expected bounds %v but got %v", expected, actual)
+		}
+	})
+
+	t.Run("honours opts.Parallelism", func(t *testing.T) {
+		img := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		expected := BoundsForThreshold(img, 0.2)
+
+		for _, parallelism := range []int{0, 1, 2, 8} {
+			result, err := Image(img, 0.2, &Options{Parallelism: parallelism})
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if actual := result.Bounds(); expected != actual {
+				t.Errorf("Expected bounds %v with parallelism %d but got %v", expected, parallelism, actual)
+			}
+		}
+	})
+
+	t.Run("preserves the original colour model via SubImage", func(t *testing.T) {
+		nrgba := loadTestImage("70x70-pink-square-on-clouds.png", t)
+
+		rgba := image.NewRGBA(nrgba.Bounds())
+		for y := nrgba.Bounds().Min.Y; y < nrgba.Bounds().Max.Y; y++ {
+			for x := nrgba.Bounds().Min.X; x < nrgba.Bounds().Max.X; x++ {
+				rgba.Set(x, y, nrgba.NRGBAAt(x, y))
+			}
+		}
+
+		result, err := Image(rgba, 0.2, nil)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if _, ok := result.(*image.RGBA); !ok {
+			t.Errorf("Expected result to share the original *image.RGBA buffer via SubImage but got %T", result)
+		}
+	})
+}
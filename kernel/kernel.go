@@ -0,0 +1,88 @@
+// Package kernel provides small convolution kernels for measuring
+// directional image gradients, for use as the energy function in
+// autocrop.
+package kernel
+
+// Kernel is a square convolution kernel with an odd side length of
+// 2*Radius()+1, such as the 3x3 Sobel or Scharr operators.
+type Kernel interface {
+
+	// Weights returns the kernel's weights in row-major order, covering a
+	// (2*Radius()+1) x (2*Radius()+1) neighbourhood centred on the pixel
+	// being convolved.
+	Weights() []float32
+
+	// Radius returns the kernel's radius; a 3x3 kernel has a radius of 1.
+	Radius() int
+}
+
+type matrix struct {
+	weights []float32
+	radius  int
+}
+
+func (m matrix) Weights() []float32 { return m.weights }
+func (m matrix) Radius() int        { return m.radius }
+
+// New returns a Kernel with the given weights (in row-major order) and
+// radius, allowing callers to define their own operators alongside the
+// ones provided by this package.
+func New(weights []float32, radius int) Kernel {
+	return matrix{weights: weights, radius: radius}
+}
+
+var (
+	// SobelX is the horizontal Sobel gradient kernel.
+	SobelX Kernel = matrix{
+		weights: []float32{
+			-1, 0, 1,
+			-2, 0, 2,
+			-1, 0, 1,
+		},
+		radius: 1,
+	}
+
+	// SobelY is the vertical Sobel gradient kernel.
+	SobelY Kernel = matrix{
+		weights: []float32{
+			-1, -2, -1,
+			0, 0, 0,
+			1, 2, 1,
+		},
+		radius: 1,
+	}
+
+	// ScharrX is the horizontal Scharr gradient kernel. Scharr weighs its
+	// on-axis neighbours more heavily relative to its diagonal ones than
+	// Sobel does (10:3 rather than 2:1), giving better rotational symmetry.
+	ScharrX Kernel = matrix{
+		weights: []float32{
+			-3, 0, 3,
+			-10, 0, 10,
+			-3, 0, 3,
+		},
+		radius: 1,
+	}
+
+	// ScharrY is the vertical Scharr gradient kernel.
+	ScharrY Kernel = matrix{
+		weights: []float32{
+			-3, -10, -3,
+			0, 0, 0,
+			3, 10, 3,
+		},
+		radius: 1,
+	}
+
+	// Laplacian5 is the 5-point discrete Laplacian kernel. Unlike the
+	// directional pairs above, it's isotropic and can be used on its own as
+	// a single measure of local contrast.
+	Laplacian5 Kernel = matrix{
+		weights: []float32{
+			0, -1, 0,
+			-1, 4, -1,
+			0, -1, 0,
+		},
+		radius: 1,
+	}
+)
@@ -0,0 +1,35 @@
+package kernel
+
+import "testing"
+
+func TestKernels(t *testing.T) {
+
+	kernels := map[string]Kernel{
+		"SobelX":     SobelX,
+		"SobelY":     SobelY,
+		"ScharrX":    ScharrX,
+		"ScharrY":    ScharrY,
+		"Laplacian5": Laplacian5,
+	}
+
+	for name, k := range kernels {
+		t.Run(name+" has weights matching its radius", func(t *testing.T) {
+			side := 2*k.Radius() + 1
+
+			if expected, actual := side*side, len(k.Weights()); expected != actual {
+				t.Errorf("Expected %d weights for radius %d but found %d", expected, k.Radius(), actual)
+			}
+		})
+	}
+
+	t.Run("New constructs a Kernel from arbitrary weights", func(t *testing.T) {
+		k := New([]float32{1, 2, 3, 4, 5, 6, 7, 8, 9}, 1)
+
+		if expected, actual := 1, k.Radius(); expected != actual {
+			t.Errorf("Expected radius %d but got %d", expected, actual)
+		}
+		if expected, actual := 9, len(k.Weights()); expected != actual {
+			t.Errorf("Expected %d weights but got %d", expected, actual)
+		}
+	})
+}
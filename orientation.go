@@ -0,0 +1,235 @@
+package autocrop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// orientationIdentity is the default EXIF orientation tag value, indicating
+// that no transform is required.
+const orientationIdentity = 1
+
+// exifHeader identifies an APP1 segment as carrying Exif metadata, as
+// opposed to e.g. XMP.
+var exifHeader = []byte("Exif\x00\x00")
+
+// ReadFromReader decodes an image from r, corrects its orientation
+// according to any EXIF orientation tag present (for JPEG sources), and
+// returns it cropped using the given energy threshold.
+//
+// This combines decoding, orientation correction and cropping in one step
+// so that auto-cropping a phone photo doesn't produce sideways output, the
+// way it would if the EXIF orientation were ignored.
+func ReadFromReader(r io.Reader, energyThreshold float32, opts *Options) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if orientation := jpegOrientation(data); orientation != orientationIdentity {
+		img = applyOrientation(img, orientation)
+	}
+
+	return Image(img, energyThreshold, opts)
+}
+
+// jpegOrientation returns the EXIF orientation tag (1-8) found in data's
+// APP1 segment, or orientationIdentity if data isn't a JPEG, carries no
+// Exif metadata, or has no orientation tag.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return orientationIdentity
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return orientationIdentity
+		}
+		marker := data[i+1]
+
+		// SOS marks the start of entropy-coded scan data; everything of
+		// interest precedes it.
+		if marker == 0xDA {
+			return orientationIdentity
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		segmentStart := i + 4
+		segmentEnd := i + 2 + length
+		if length < 2 || segmentEnd > len(data) {
+			return orientationIdentity
+		}
+
+		if marker == 0xE1 {
+			if orientation, ok := exifOrientation(data[segmentStart:segmentEnd]); ok {
+				return orientation
+			}
+		}
+
+		i = segmentEnd
+	}
+
+	return orientationIdentity
+}
+
+// exifOrientation parses the Orientation tag (0x0112) out of the TIFF
+// structure of an Exif APP1 segment.
+func exifOrientation(segment []byte) (orientation int, ok bool) {
+	if len(segment) < len(exifHeader)+8 || !bytes.Equal(segment[:len(exifHeader)], exifHeader) {
+		return 0, false
+	}
+	tiff := segment[len(exifHeader):]
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	ifdOffset := order.Uint32(tiff[4:8])
+	if int(ifdOffset)+2 > len(tiff) {
+		return 0, false
+	}
+
+	entryCount := int(order.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	entriesStart := int(ifdOffset) + 2
+
+	for e := 0; e < entryCount; e++ {
+		entryStart := entriesStart + e*12
+		if entryStart+12 > len(tiff) {
+			break
+		}
+
+		tag := order.Uint16(tiff[entryStart : entryStart+2])
+		if tag == 0x0112 {
+			value := order.Uint16(tiff[entryStart+8 : entryStart+10])
+			if value >= 1 && value <= 8 {
+				return int(value), true
+			}
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
+
+// applyOrientation returns img transformed according to the given EXIF
+// orientation tag (1-8), or img unchanged if the orientation is identity or
+// unrecognised.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return flipH(img)
+	case 3:
+		return rotate180(img)
+	case 4:
+		return flipV(img)
+	case 5:
+		return transpose(img)
+	case 6:
+		return rotate90CW(img)
+	case 7:
+		return transverse(img)
+	case 8:
+		return rotate90CCW(img)
+	default:
+		return img
+	}
+}
+
+func flipH(img image.Image) image.Image {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			result.Set(b.Dx()-1-x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return result
+}
+
+func flipV(img image.Image) image.Image {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			result.Set(x, b.Dy()-1-y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return result
+}
+
+func rotate180(img image.Image) image.Image {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			result.Set(b.Dx()-1-x, b.Dy()-1-y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return result
+}
+
+// rotate90CW rotates img 90 degrees clockwise (EXIF orientation 6).
+func rotate90CW(img image.Image) image.Image {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			result.Set(b.Dy()-1-y, x, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return result
+}
+
+// rotate90CCW rotates img 90 degrees counter-clockwise (EXIF orientation
+// 8).
+func rotate90CCW(img image.Image) image.Image {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			result.Set(y, b.Dx()-1-x, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return result
+}
+
+// transpose flips img across its top-left/bottom-right diagonal (EXIF
+// orientation 5).
+func transpose(img image.Image) image.Image {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			result.Set(y, x, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return result
+}
+
+// transverse flips img across its top-right/bottom-left diagonal (EXIF
+// orientation 7).
+func transverse(img image.Image) image.Image {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			result.Set(b.Dy()-1-y, b.Dx()-1-x, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package autocrop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+)
+
+// buildExifApp1 builds a minimal JPEG APP1 segment body (as would follow
+// the marker and length bytes) carrying a single Orientation tag.
+func buildExifApp1(orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	copy(tiff[0:2], "II")
+	binary.LittleEndian.PutUint16(tiff[2:4], 42)
+	binary.LittleEndian.PutUint32(tiff[4:8], 8)
+	binary.LittleEndian.PutUint16(tiff[8:10], 1)
+
+	entry := tiff[10:22]
+	binary.LittleEndian.PutUint16(entry[0:2], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:4], 3)
+	binary.LittleEndian.PutUint32(entry[4:8], 1)
+	binary.LittleEndian.PutUint16(entry[8:10], orientation)
+
+	return append(append([]byte{}, exifHeader...), tiff...)
+}
+
+func buildJPEGWithOrientation(orientation uint16) []byte {
+	app1 := buildExifApp1(orientation)
+
+	data := []byte{0xFF, 0xD8}
+	data = append(data, 0xFF, 0xE1)
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(app1)+2))
+	data = append(data, length...)
+	data = append(data, app1...)
+	data = append(data, 0xFF, 0xDA)
+
+	return data
+}
+
+// buildRealJPEGWithOrientation encodes a genuine, decodable JPEG with a
+// high-energy 8x8 square in its top-left corner, carrying an Exif APP1
+// segment with the given orientation tag, for exercising ReadFromReader
+// end-to-end.
+func buildRealJPEGWithOrientation(orientation uint16) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.NRGBA{R: 255, G: 255, B: 255, A: 255}}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 8, 8), &image.Uniform{C: color.NRGBA{R: 228, A: 255}}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		panic(err)
+	}
+	data := buf.Bytes()
+
+	app1 := buildExifApp1(orientation)
+	segment := []byte{0xFF, 0xE1}
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(app1)+2))
+	segment = append(segment, length...)
+	segment = append(segment, app1...)
+
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestReadFromReader(t *testing.T) {
+
+	t.Run("decodes, re-orients and crops a JPEG end-to-end", func(t *testing.T) {
+		data := buildRealJPEGWithOrientation(6)
+
+		result, err := ReadFromReader(bytes.NewReader(data), 0.1, nil)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		// Orientation 6 rotates 90 degrees clockwise, moving the square from
+		// the top-left to the top-right corner; autocropping should then
+		// remove the now-plain left and bottom borders.
+		if expected, actual := image.Rect(8, 0, 16, 8), result.Bounds(); expected != actual {
+			t.Errorf("Expected bounds %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestJpegOrientation(t *testing.T) {
+
+	t.Run("returns identity for non-JPEG data", func(t *testing.T) {
+		if expected, actual := orientationIdentity, jpegOrientation([]byte{0x89, 0x50, 0x4E, 0x47}); expected != actual {
+			t.Errorf("Expected orientation %d but got %d", expected, actual)
+		}
+	})
+
+	t.Run("returns identity for JPEG without Exif metadata", func(t *testing.T) {
+		data := []byte{0xFF, 0xD8, 0xFF, 0xDA}
+
+		if expected, actual := orientationIdentity, jpegOrientation(data); expected != actual {
+			t.Errorf("Expected orientation %d but got %d", expected, actual)
+		}
+	})
+
+	t.Run("returns the orientation tag from an Exif APP1 segment", func(t *testing.T) {
+		for orientation := 1; orientation <= 8; orientation++ {
+			data := buildJPEGWithOrientation(uint16(orientation))
+
+			if expected, actual := orientation, jpegOrientation(data); expected != actual {
+				t.Errorf("Expected orientation %d but got %d", expected, actual)
+			}
+		}
+	})
+}
+
+func TestApplyOrientation(t *testing.T) {
+
+	t.Run("leaves the image unchanged for identity orientation", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+		img.Set(1, 0, color.NRGBA{G: 255, A: 255})
+
+		result := applyOrientation(img, orientationIdentity)
+
+		if result != image.Image(img) {
+			t.Errorf("Expected identity orientation to return the image unchanged")
+		}
+	})
+
+	t.Run("rotates a non-square image 90 degrees clockwise", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+		img.Set(1, 0, color.NRGBA{G: 255, A: 255})
+
+		result := applyOrientation(img, 6)
+
+		if expected, actual := 1, result.Bounds().Dx(); expected != actual {
+			t.Errorf("Expected rotated width %d but got %d", expected, actual)
+		}
+		if expected, actual := 2, result.Bounds().Dy(); expected != actual {
+			t.Errorf("Expected rotated height %d but got %d", expected, actual)
+		}
+
+		r, g, b, a := result.At(0, 0).RGBA()
+		if r == 0 || g != 0 || b != 0 || a == 0 {
+			t.Errorf("Expected top-left pixel to be the original top-left (red) pixel, got (%d,%d,%d,%d)", r, g, b, a)
+		}
+	})
+}
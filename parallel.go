@@ -0,0 +1,160 @@
+package autocrop
+
+import (
+	"image"
+	"runtime"
+	"sync"
+)
+
+// BoundsForThresholdWithOptions is identical to BoundsForThreshold, except
+// that it computes energies across opts.Parallelism goroutines instead of
+// a single one, measuring them with opts.EnergyKernel and opts.EnergyNorm.
+func BoundsForThresholdWithOptions(img *image.NRGBA, energyThreshold float32, opts Options) image.Rectangle {
+
+	crop := img.Bounds()
+
+	radius := opts.EnergyKernel.radius()
+	energyCrop := crop
+	energyCrop.Min.X += radius
+	energyCrop.Min.Y += radius
+	energyCrop.Max.X -= radius
+	energyCrop.Max.Y -= radius
+
+	if energyCrop.Empty() {
+		return img.Bounds()
+	}
+
+	colEnergies, rowEnergies := parallelEnergies(img, energyCrop, opts)
+
+	// Find left and right high energy jumps
+	maxEnergy := findMaxEnergy(colEnergies)
+	cropLeft := findFirstEnergyBound(colEnergies, maxEnergy, energyThreshold)
+	cropRight := findLastEnergyBound(colEnergies, maxEnergy, energyThreshold, cropLeft)
+
+	// Find top and bottom high energy jumps
+	maxEnergy = findMaxEnergy(rowEnergies)
+	cropTop := findFirstEnergyBound(rowEnergies, maxEnergy, energyThreshold)
+	cropBottom := findLastEnergyBound(rowEnergies, maxEnergy, energyThreshold, cropTop)
+
+	// Apply the crop
+	crop.Min.X += cropLeft
+	crop.Min.Y += cropTop
+	crop.Max.X -= cropRight
+	crop.Max.Y -= cropBottom
+
+	return crop
+}
+
+// parallelEnergies is energiesWithKernel, but split into horizontal
+// stripes (height / opts.Parallelism) which are each computed on their own
+// goroutine. Each stripe is widened by energiesWithKernel's own overlap
+// (opts.EnergyKernel's radius on each side), giving every worker full
+// neighbourhoods for the pixels along its stripe's edges.
+//
+// opts.Parallelism <= 0 defaults to runtime.NumCPU().
+func parallelEnergies(img *image.NRGBA, r image.Rectangle, opts Options) (cols, rows []float32) {
+
+	parallelism := resolveParallelism(opts.Parallelism, r.Dy())
+
+	cols = make([]float32, r.Dx(), r.Dx())
+	rows = make([]float32, r.Dy(), r.Dy())
+
+	stripeHeight := (r.Dy() + parallelism - 1) / parallelism
+	partialCols := make([][]float32, parallelism)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < parallelism; w++ {
+		stripeMinY := r.Min.Y + w*stripeHeight
+		stripeMaxY := stripeMinY + stripeHeight
+		if stripeMaxY > r.Max.Y {
+			stripeMaxY = r.Max.Y
+		}
+		if stripeMinY >= stripeMaxY {
+			continue
+		}
+
+		wg.Add(1)
+		go func(w int, stripe image.Rectangle) {
+			defer wg.Done()
+
+			stripeCols, stripeRows := energiesWithKernel(img, stripe, opts.EnergyKernel, opts.EnergyNorm)
+			partialCols[w] = stripeCols
+			copy(rows[stripe.Min.Y-r.Min.Y:stripe.Max.Y-r.Min.Y], stripeRows)
+		}(w, image.Rect(r.Min.X, stripeMinY, r.Max.X, stripeMaxY))
+	}
+
+	wg.Wait()
+
+	for _, stripeCols := range partialCols {
+		for i, e := range stripeCols {
+			cols[i] += e
+		}
+	}
+
+	return cols, rows
+}
+
+// resolveParallelism clamps a requested parallelism to a sane number of
+// stripes for an image of the given height: at least 1, at most one
+// stripe per row, and defaulting to runtime.NumCPU() when requested is
+// zero or negative.
+func resolveParallelism(requested, height int) int {
+	parallelism := requested
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	if parallelism > height {
+		parallelism = height
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return parallelism
+}
+
+// parallelEnergiesGeneric is parallelEnergies, but for an arbitrary
+// image.Image rather than an *image.NRGBA, using energiesGeneric to
+// compute each stripe.
+func parallelEnergiesGeneric(img image.Image, r image.Rectangle, opts Options) (cols, rows []float32) {
+
+	parallelism := resolveParallelism(opts.Parallelism, r.Dy())
+
+	cols = make([]float32, r.Dx(), r.Dx())
+	rows = make([]float32, r.Dy(), r.Dy())
+
+	stripeHeight := (r.Dy() + parallelism - 1) / parallelism
+	partialCols := make([][]float32, parallelism)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < parallelism; w++ {
+		stripeMinY := r.Min.Y + w*stripeHeight
+		stripeMaxY := stripeMinY + stripeHeight
+		if stripeMaxY > r.Max.Y {
+			stripeMaxY = r.Max.Y
+		}
+		if stripeMinY >= stripeMaxY {
+			continue
+		}
+
+		wg.Add(1)
+		go func(w int, stripe image.Rectangle) {
+			defer wg.Done()
+
+			stripeCols, stripeRows := energiesGeneric(img, stripe, opts.EnergyKernel, opts.EnergyNorm)
+			partialCols[w] = stripeCols
+			copy(rows[stripe.Min.Y-r.Min.Y:stripe.Max.Y-r.Min.Y], stripeRows)
+		}(w, image.Rect(r.Min.X, stripeMinY, r.Max.X, stripeMaxY))
+	}
+
+	wg.Wait()
+
+	for _, stripeCols := range partialCols {
+		for i, e := range stripeCols {
+			cols[i] += e
+		}
+	}
+
+	return cols, rows
+}